@@ -0,0 +1,102 @@
+// Command rollout deploys a change to a named workload and waits for it to
+// become ready, rolling back automatically if it doesn't. Unlike the
+// exercises/deploy.go script it grew out of, it doesn't hardcode Deployments
+// in the default namespace: the workload Kind is discovered via RESTMapper,
+// so the same binary drives a StatefulSet or DaemonSet rollout too.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/de1ux/kubernetes_exercises/pkg/statuscheck"
+	"github.com/de1ux/kubernetes_exercises/pkg/waiter"
+	"github.com/de1ux/kubernetes_exercises/pkg/workload"
+)
+
+const rolloutReadyThreshold = 2 * time.Minute
+
+func main() {
+	namespace := flag.String("namespace", "default", "namespace of the workload")
+	resource := flag.String("resource", "deployment", "workload Kind or resource name, e.g. deployment, statefulset, daemonset")
+	name := flag.String("name", "", "workload name")
+	image := flag.String("image", "", "container image to set on the first container")
+	flag.Parse()
+
+	if *name == "" || *image == "" {
+		panic("rollout: -name and -image are required")
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(homedir.HomeDir(), ".kube", "config"))
+	if err != nil {
+		panic(err)
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := workload.Discover(clientSet, *namespace, *resource, *name)
+	if err != nil {
+		panic(err)
+	}
+
+	current := w.GetPodTemplate()
+	previous := *current.DeepCopy()
+	revision := w.ObservedRevision()
+
+	template := *previous.DeepCopy()
+	template.Spec.Containers[0].Image = *image
+	w.SetPodTemplate(template)
+	if err := w.Save(); err != nil {
+		panic(err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(w.Selector())
+	if err != nil {
+		panic(fmt.Errorf("rollout: invalid selector on %s: %w", *name, err))
+	}
+
+	if err := waitReady(clientSet, *namespace, selector.String()); err != nil {
+		fmt.Printf("rollout did not become ready, rolling back: %s\n", err)
+		if revision != 0 {
+			if err := w.Rollback(revision); err != nil {
+				panic(fmt.Errorf("rollout: rollback to revision %d failed: %w", revision, err))
+			}
+		} else {
+			w.SetPodTemplate(previous)
+			if err := w.Save(); err != nil {
+				panic(fmt.Errorf("rollout: rollback failed: %w", err))
+			}
+		}
+		if err := waitReady(clientSet, *namespace, selector.String()); err != nil {
+			panic(fmt.Errorf("rollout: rollback did not become ready: %w", err))
+		}
+		println("Rolled back successfully!")
+		return
+	}
+
+	println("Deploy successful")
+}
+
+func waitReady(clientSet *kubernetes.Clientset, namespace, selector string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rolloutReadyThreshold)
+	defer cancel()
+
+	checker := statuscheck.NewChecker(clientSet, statuscheck.Options{})
+	w := waiter.New(clientSet, waiter.DefaultBackoff)
+
+	return w.WaitForPods(ctx, namespace, selector, func(obj runtime.Object) (bool, error) {
+		return checker.IsReady(obj)
+	})
+}
@@ -0,0 +1,65 @@
+// This mirrors deploy.go's redis:doesntexist demo, but drives it through
+// strategy.Canary instead of a single replace-then-wait: the bad image is
+// only ever run at 10% before the readiness wait catches it and it's rolled
+// back, rather than taking down every pod in the Deployment.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/de1ux/kubernetes_exercises/pkg/strategy"
+)
+
+func main() {
+	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(homedir.HomeDir(), ".kube", "config"))
+	if err != nil {
+		panic(err)
+	}
+
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
+	dClient := clientSet.AppsV1().Deployments("default")
+	original, err := dClient.Get("redis", metav1.GetOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	badTemplate := *original.Spec.Template.DeepCopy()
+	badTemplate.Spec.Containers[0].Image = "redis:doesntexist"
+
+	canary := strategy.Canary{
+		Client:    clientSet,
+		Namespace: "default",
+		Timeout:   30 * time.Second,
+		Config: strategy.CanaryConfig{
+			Steps:        []int{10, 50, 100},
+			StepDuration: 5 * time.Second,
+			ProbeFn: func() error {
+				// A real probe would hit a health endpoint through
+				// port-forward or a Job; here readiness alone is enough to
+				// demonstrate the rollback path.
+				return nil
+			},
+			PostRollback: func(err error) {
+				println(fmt.Sprintf("Canary rolled back: %s", err))
+			},
+		},
+	}
+
+	if err := canary.Rollout("redis", badTemplate); err != nil {
+		println(fmt.Sprintf("Rolled back successfully: %s", err))
+		return
+	}
+
+	println("Deploy successful")
+}
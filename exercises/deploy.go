@@ -1,24 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"time"
 
-	apiv1 "k8s.io/api/extensions/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
-	v1 "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
+	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 	"k8s.io/client-go/util/retry"
-)
 
-const (
-	deployRunningThreshold     = time.Second * 10
-	deployRunningCheckInterval = time.Second * 2
+	"github.com/de1ux/kubernetes_exercises/pkg/statuscheck"
+	"github.com/de1ux/kubernetes_exercises/pkg/waiter"
+	"github.com/de1ux/kubernetes_exercises/pkg/workload"
 )
 
+const deployRunningThreshold = time.Second * 10
+
 func main() {
 	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(homedir.HomeDir(), ".kube", "config"))
 	if err != nil {
@@ -30,20 +33,23 @@ func main() {
 		panic(err)
 	}
 
-	dClient := clientSet.ExtensionsV1beta1().Deployments("default")
+	dClient := clientSet.AppsV1().Deployments("default")
 
-	// Get a copy of the current deployment
+	// Get a copy of the current deployment, and note its revision so we can
+	// roll back to it later without holding the rest of its spec in memory.
 	originalDeployment, err := dClient.Get("redis", metav1.GetOptions{})
 	if err != nil {
 		panic(err)
 	}
+	rollbackRevision := workload.NewDeployment(clientSet, originalDeployment).ObservedRevision()
 
 	// Verify the current containers in the pod are running
-	if allRunning, err := podContainersRunning(clientSet, "redis"); !(allRunning && err == nil) {
+	checker := statuscheck.NewChecker(clientSet, statuscheck.Options{})
+	if allRunning, err := podContainersRunning(clientSet, checker, "redis"); !(allRunning && err == nil) {
 		panic(fmt.Sprintf("Not all containers are currently running, or err: %s", err))
 	}
 
-	if err := deploy(dClient, "redis", func(deployment *apiv1.Deployment) {
+	if err := deploy(dClient, "redis", func(deployment *appsv1.Deployment) {
 		deployment.Spec.Template.Spec.Containers[0].Image = "redis:doesntexist"
 	}); err != nil {
 		panic(err)
@@ -55,10 +61,14 @@ func main() {
 		println("Deploy successful")
 	}
 
-	// Try rolling back
-	if err := deploy(dClient, "redis", func(deployment *apiv1.Deployment) {
-		deployment.Spec.Template.Spec.Containers[0].Image = originalDeployment.Spec.Template.Spec.Containers[0].Image
-	}); err != nil {
+	// Roll back by reconstructing the prior revision's pod template from its
+	// ReplicaSet, rather than reapplying the in-memory originalDeployment -
+	// the latter only works because this process never restarted mid-update.
+	current, err := dClient.Get("redis", metav1.GetOptions{})
+	if err != nil {
+		panic(err)
+	}
+	if err := workload.NewDeployment(clientSet, current).Rollback(rollbackRevision); err != nil {
 		panic(err)
 	}
 
@@ -69,7 +79,7 @@ func main() {
 	println("Rolled back successfully!")
 }
 
-func deploy(dClient v1.DeploymentInterface, app string, op func(deployment *apiv1.Deployment)) error {
+func deploy(dClient v1.DeploymentInterface, app string, op func(deployment *appsv1.Deployment)) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		result, err := dClient.Get(app, metav1.GetOptions{})
 		if err != nil {
@@ -83,30 +93,30 @@ func deploy(dClient v1.DeploymentInterface, app string, op func(deployment *apiv
 	})
 }
 
+// waitForPodContainersRunning blocks until every pod for app is reported
+// ready by statuscheck, or deployRunningThreshold elapses. It is a thin
+// wrapper around waiter.Waiter, which watches for pod changes instead of
+// polling on a fixed interval.
 func waitForPodContainersRunning(clientSet *kubernetes.Clientset, app string) error {
-	end := time.Now().Add(deployRunningThreshold)
-
-	for true {
-		<-time.NewTimer(deployRunningCheckInterval).C
-
-		var err error
-		running, err := podContainersRunning(clientSet, app)
-		if running {
-			return nil
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), deployRunningThreshold)
+	defer cancel()
 
-		if err != nil {
-			println(fmt.Sprintf("Encountered an error checking for running pods: %s", err))
-		}
+	checker := statuscheck.NewChecker(clientSet, statuscheck.Options{})
+	w := waiter.New(clientSet, waiter.DefaultBackoff)
 
-		if time.Now().After(end) {
-			return fmt.Errorf("Failed to get all running containers")
-		}
+	err := w.WaitForPods(ctx, "default", fmt.Sprintf("app=%s", app), func(obj runtime.Object) (bool, error) {
+		return checker.IsReady(obj)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to get all running containers: %w", err)
 	}
 	return nil
 }
 
-func podContainersRunning(clientSet *kubernetes.Clientset, app string) (bool, error) {
+// podContainersRunning reports whether every pod matching app=<app> is ready,
+// per statuscheck's Pod readiness rules (Phase == Running and all
+// ContainerStatuses Ready).
+func podContainersRunning(clientSet *kubernetes.Clientset, checker *statuscheck.Checker, app string) (bool, error) {
 	pods, err := clientSet.CoreV1().Pods("default").List(metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("app=%s", app),
 	})
@@ -114,11 +124,13 @@ func podContainersRunning(clientSet *kubernetes.Clientset, app string) (bool, er
 		return false, err
 	}
 
-	for _, item := range pods.Items {
-		for _, status := range item.Status.ContainerStatuses {
-			if !status.Ready {
-				return false, nil
-			}
+	for i := range pods.Items {
+		ready, err := checker.IsReady(&pods.Items[i])
+		if err != nil {
+			return false, err
+		}
+		if !ready {
+			return false, nil
 		}
 	}
 	return true, nil
@@ -0,0 +1,221 @@
+// Package statuscheck provides a Kind-aware readiness checker for Kubernetes
+// resources, modeled after Helm's kube.ReadyChecker. Unlike a generic
+// "list pods and check ContainerStatuses" loop, it understands what "ready"
+// means for each Kind it is handed.
+package statuscheck
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// deploymentRevisionAnnotation is set by the Deployment controller on both
+// the Deployment itself and every ReplicaSet it creates, recording which
+// generation the ReplicaSet matches. It is how deploymentReady identifies
+// the Deployment's current ReplicaSet.
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Options controls how the Checker treats edge cases that differ between
+// callers (e.g. `kubectl apply --wait` vs. a long-lived controller).
+type Options struct {
+	// PausedAsReady treats a Deployment/StatefulSet with updates paused as
+	// ready rather than blocking forever waiting for a rollout that will
+	// never complete.
+	PausedAsReady bool
+	// CheckJobs requires Jobs to have reached their Completions count
+	// before being considered ready. When false, Jobs are always ready.
+	CheckJobs bool
+}
+
+// Checker reports whether a Kubernetes object has reached a ready state,
+// dispatching on its concrete Kind.
+type Checker struct {
+	client kubernetes.Interface
+	opts   Options
+}
+
+// NewChecker returns a Checker that queries the cluster through client.
+func NewChecker(client kubernetes.Interface, opts Options) *Checker {
+	return &Checker{client: client, opts: opts}
+}
+
+// IsReady reports whether obj has reached a ready state. It returns an error
+// if obj's Kind is not one this Checker knows how to evaluate, or if the
+// cluster could not be queried for supporting objects (e.g. a Deployment's
+// ReplicaSets).
+func (c *Checker) IsReady(obj runtime.Object) (bool, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return c.deploymentReady(o)
+	case *appsv1.StatefulSet:
+		return c.statefulSetReady(o), nil
+	case *appsv1.DaemonSet:
+		return c.daemonSetReady(o), nil
+	case *corev1.Pod:
+		return podReady(o), nil
+	case *corev1.PersistentVolumeClaim:
+		return pvcReady(o), nil
+	case *corev1.Service:
+		return serviceReady(o), nil
+	case *batchv1.Job:
+		return c.jobReady(o), nil
+	case *extv1.CustomResourceDefinition:
+		return crdReady(o), nil
+	default:
+		return false, fmt.Errorf("statuscheck: no readiness check for %T", obj)
+	}
+}
+
+// deploymentReady requires the Deployment to have observed its latest spec
+// and rolled it out, and additionally walks down to the ReplicaSet actually
+// owning the newest pods so a stuck rollout is not reported healthy just
+// because an old ReplicaSet's pods are still Ready.
+func (c *Checker) deploymentReady(d *appsv1.Deployment) (bool, error) {
+	if c.opts.PausedAsReady && d.Spec.Paused {
+		return true, nil
+	}
+
+	if d.Generation > d.Status.ObservedGeneration {
+		return false, nil
+	}
+	if d.Spec.Replicas != nil && d.Status.UpdatedReplicas < *d.Spec.Replicas {
+		return false, nil
+	}
+	expectedReplicas := int32(1)
+	if d.Spec.Replicas != nil {
+		expectedReplicas = *d.Spec.Replicas
+	}
+	if d.Status.AvailableReplicas < expectedReplicas {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("statuscheck: invalid selector on %s: %w", d.Name, err)
+	}
+	rsList, err := c.client.AppsV1().ReplicaSets(d.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("statuscheck: listing ReplicaSets for %s: %w", d.Name, err)
+	}
+
+	rsPtrs := make([]*appsv1.ReplicaSet, 0, len(rsList.Items))
+	for i := range rsList.Items {
+		rsPtrs = append(rsPtrs, &rsList.Items[i])
+	}
+
+	newRS := newReplicaSet(d, rsPtrs)
+	if newRS == nil {
+		// The new ReplicaSet has not been created yet.
+		return false, nil
+	}
+	if newRS.Status.ReadyReplicas < expectedReplicas {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// newReplicaSet returns the ReplicaSet among rsList that the Deployment
+// controller created for d's current generation, identified by both of them
+// carrying the same deploymentRevisionAnnotation value.
+func newReplicaSet(d *appsv1.Deployment, rsList []*appsv1.ReplicaSet) *appsv1.ReplicaSet {
+	current := d.Annotations[deploymentRevisionAnnotation]
+	if current == "" {
+		return nil
+	}
+	for _, rs := range rsList {
+		if metav1.IsControlledBy(rs, d) && rs.Annotations[deploymentRevisionAnnotation] == current {
+			return rs
+		}
+	}
+	return nil
+}
+
+func (c *Checker) statefulSetReady(s *appsv1.StatefulSet) bool {
+	if c.opts.PausedAsReady && s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+	// CurrentRevision only catches up with UpdateRevision once every replica
+	// runs it, which a partitioned rollout never does.
+	partitioned := s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil &&
+		*s.Spec.UpdateStrategy.RollingUpdate.Partition > 0
+	if !partitioned && s.Status.CurrentRevision != s.Status.UpdateRevision {
+		return false
+	}
+
+	expectedReplicas := int32(1)
+	if s.Spec.Replicas != nil {
+		expectedReplicas = *s.Spec.Replicas
+	}
+
+	// A partitioned RollingUpdate only rolls out replicas with an ordinal
+	// >= Partition, so only that many are required to be the new revision.
+	if s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition := *s.Spec.UpdateStrategy.RollingUpdate.Partition
+		if expectedReplicas-partition > s.Status.UpdatedReplicas {
+			return false
+		}
+	}
+
+	return s.Status.ReadyReplicas >= expectedReplicas
+}
+
+func (c *Checker) daemonSetReady(d *appsv1.DaemonSet) bool {
+	if c.opts.PausedAsReady && d.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return true
+	}
+	return d.Status.NumberReady >= d.Status.DesiredNumberScheduled &&
+		d.Status.UpdatedNumberScheduled >= d.Status.DesiredNumberScheduled
+}
+
+func podReady(p *corev1.Pod) bool {
+	if p.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, status := range p.Status.ContainerStatuses {
+		if !status.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+func pvcReady(p *corev1.PersistentVolumeClaim) bool {
+	return p.Status.Phase == corev1.ClaimBound
+}
+
+func serviceReady(s *corev1.Service) bool {
+	if s.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		return len(s.Status.LoadBalancer.Ingress) > 0
+	}
+	return s.Spec.ClusterIP != ""
+}
+
+func (c *Checker) jobReady(j *batchv1.Job) bool {
+	if !c.opts.CheckJobs {
+		return true
+	}
+	expected := int32(1)
+	if j.Spec.Completions != nil {
+		expected = *j.Spec.Completions
+	}
+	return j.Status.Succeeded >= expected
+}
+
+func crdReady(crd *extv1.CustomResourceDefinition) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == extv1.Established {
+			return cond.Status == extv1.ConditionTrue
+		}
+	}
+	return false
+}
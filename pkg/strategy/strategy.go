@@ -0,0 +1,269 @@
+// Package strategy generalizes deploy() into pluggable rollout strategies,
+// culminating in a Canary strategy that scales a new pod template up
+// gradually and rolls back automatically on failure, rather than the
+// all-or-nothing replace-then-wait the exercise originally demonstrated by
+// hand.
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/de1ux/kubernetes_exercises/pkg/statuscheck"
+	"github.com/de1ux/kubernetes_exercises/pkg/waiter"
+)
+
+// Strategy drives how a new pod template is rolled out to a Deployment.
+type Strategy interface {
+	// Rollout updates app's pod template to template and blocks until the
+	// rollout has settled, either promoted or rolled back.
+	Rollout(app string, template corev1.PodTemplateSpec) error
+}
+
+// Recreate applies template directly and waits for it to become ready. The
+// actual replacement order is controlled by the Deployment's own
+// Spec.Strategy, not by this type; Recreate just drives it and blocks.
+type Recreate struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Timeout   time.Duration
+}
+
+func (s Recreate) Rollout(app string, template corev1.PodTemplateSpec) error {
+	if err := s.update(app, template); err != nil {
+		return fmt.Errorf("strategy: recreate: %w", err)
+	}
+	return s.waitReady(app)
+}
+
+func (s Recreate) update(app string, template corev1.PodTemplateSpec) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		d, err := s.Client.AppsV1().Deployments(s.Namespace).Get(app, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		d.Spec.Template = template
+		_, err = s.Client.AppsV1().Deployments(s.Namespace).Update(d)
+		return err
+	})
+}
+
+func (s Recreate) waitReady(app string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	checker := statuscheck.NewChecker(s.Client, statuscheck.Options{})
+	w := waiter.New(s.Client, waiter.DefaultBackoff)
+
+	return w.WaitForPods(ctx, s.Namespace, fmt.Sprintf("app=%s", app), func(obj runtime.Object) (bool, error) {
+		return checker.IsReady(obj)
+	})
+}
+
+// RollingUpdate applies template and waits for readiness the same way
+// Recreate does; whether old and new pods briefly coexist is controlled by
+// the Deployment's Spec.Strategy.RollingUpdate, not by this type.
+type RollingUpdate struct {
+	Recreate
+}
+
+// CanaryConfig configures a progressive rollout.
+type CanaryConfig struct {
+	// Steps is the sequence of percentages (of the Deployment's replica
+	// count) the canary is scaled to in turn, e.g. []int{10, 50, 100}.
+	Steps []int
+	// StepDuration is how long to let a step soak after it becomes ready
+	// and its probe passes before advancing to the next one.
+	StepDuration time.Duration
+	// ProbeFn, if set, is called after each step becomes ready. A non-nil
+	// error aborts the rollout and triggers rollback.
+	ProbeFn func() error
+	// PrePromote, if set, is called before scaling the canary to each step.
+	// It is not called again when the template is finally promoted onto the
+	// original Deployment. A non-nil error aborts the rollout and triggers
+	// rollback.
+	PrePromote func(step int) error
+	// PostRollback, if set, is called once after a rollback, successful or
+	// not, with the error that triggered it.
+	PostRollback func(rollbackErr error)
+}
+
+// Canary rolls a Deployment out progressively: it stands up a second
+// Deployment ("<app>-canary") running the new template, scales it through
+// Config.Steps as a percentage of the original's replica count, and waits
+// plus probes at each step before advancing. A failing probe, or the canary
+// never becoming ready, deletes the canary Deployment and leaves the
+// original untouched; reaching the last step promotes the new template onto
+// the original Deployment and removes the canary.
+type Canary struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Timeout   time.Duration
+	Config    CanaryConfig
+}
+
+func (s Canary) Rollout(app string, template corev1.PodTemplateSpec) error {
+	dClient := s.Client.AppsV1().Deployments(s.Namespace)
+	original, err := dClient.Get(app, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("strategy: canary: get %s: %w", app, err)
+	}
+
+	total := int32(1)
+	if original.Spec.Replicas != nil {
+		total = *original.Spec.Replicas
+	}
+
+	canaryName := app + "-canary"
+	canaryTemplate := *template.DeepCopy()
+	canaryTemplate.Labels = withLabel(canaryTemplate.Labels, "track", "canary")
+
+	rollback := func(cause error) error {
+		if delErr := dClient.Delete(canaryName, &metav1.DeleteOptions{}); delErr != nil && !apierrors.IsNotFound(delErr) {
+			cause = fmt.Errorf("%w (also failed to delete canary %s: %s)", cause, canaryName, delErr)
+		}
+		if s.Config.PostRollback != nil {
+			s.Config.PostRollback(cause)
+		}
+		return cause
+	}
+
+	for _, step := range s.Config.Steps {
+		if s.Config.PrePromote != nil {
+			if err := s.Config.PrePromote(step); err != nil {
+				return rollback(fmt.Errorf("strategy: canary: pre-promote at step %d%%: %w", step, err))
+			}
+		}
+
+		replicas := (total*int32(step) + 99) / 100
+		if replicas < 1 {
+			replicas = 1
+		}
+
+		if err := s.applyCanary(dClient, original, canaryName, canaryTemplate, replicas); err != nil {
+			return rollback(fmt.Errorf("strategy: canary: scale to %d%%: %w", step, err))
+		}
+
+		if err := s.waitReady(fmt.Sprintf("app=%s,track=canary", app)); err != nil {
+			return rollback(fmt.Errorf("strategy: canary: step %d%% did not become ready: %w", step, err))
+		}
+
+		if s.Config.ProbeFn != nil {
+			if err := s.Config.ProbeFn(); err != nil {
+				return rollback(fmt.Errorf("strategy: canary: probe failed at step %d%%: %w", step, err))
+			}
+		}
+
+		time.Sleep(s.Config.StepDuration)
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := dClient.Get(app, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Spec.Template = template
+		_, err = dClient.Update(current)
+		return err
+	}); err != nil {
+		return rollback(fmt.Errorf("strategy: canary: promote: %w", err))
+	}
+
+	// Delete the canary before waiting on the promoted rollout: its pods
+	// still carry app=<app>, so a promote-wait on that selector alone would
+	// also see the (soon to be removed) canary pods and could flip ready/
+	// not-ready on their account even though the real rollout is fine.
+	if delErr := dClient.Delete(canaryName, &metav1.DeleteOptions{}); delErr != nil && !apierrors.IsNotFound(delErr) {
+		return fmt.Errorf("strategy: canary: promoted but failed to delete canary %s: %w", canaryName, delErr)
+	}
+
+	if err := s.waitReady(fmt.Sprintf("app=%s", app)); err != nil {
+		cause := fmt.Errorf("strategy: canary: promoted rollout did not become ready: %w", err)
+		// The new template is already on the original Deployment here, so
+		// rolling back means restoring the template it had before.
+		if restoreErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := dClient.Get(app, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			current.Spec.Template = original.Spec.Template
+			_, err = dClient.Update(current)
+			return err
+		}); restoreErr != nil {
+			cause = fmt.Errorf("%w (also failed to restore %s: %s)", cause, app, restoreErr)
+		}
+		return rollback(cause)
+	}
+	return nil
+}
+
+// applyCanary creates or scales+updates the canary Deployment to replicas
+// running template, copying metadata (labels, selector) from original so
+// its pods stay compatible with the same Service.
+func (s Canary) applyCanary(dClient v1.DeploymentInterface, original *appsv1.Deployment, canaryName string, template corev1.PodTemplateSpec, replicas int32) error {
+	_, err := dClient.Get(canaryName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		canary := original.DeepCopy()
+		canary.Name = canaryName
+		canary.ResourceVersion = ""
+		canary.UID = ""
+		canary.Spec.Replicas = &replicas
+		canary.Spec.Template = template
+		if canary.Spec.Selector != nil {
+			canary.Spec.Selector = canary.Spec.Selector.DeepCopy()
+			canary.Spec.Selector.MatchLabels = withLabel(canary.Spec.Selector.MatchLabels, "track", "canary")
+		}
+		_, err := dClient.Create(canary)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("strategy: canary: get %s: %w", canaryName, err)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := dClient.Get(canaryName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Spec.Replicas = &replicas
+		current.Spec.Template = template
+		_, err = dClient.Update(current)
+		return err
+	})
+}
+
+// waitReady blocks until every pod matching selector is ready. Unlike
+// Recreate.waitReady, the caller builds the selector: the canary Deployment
+// during its steps is only identifiable by its "track=canary" label, not by
+// "app=<canaryName>" (its pod template's "app" label is copied from the
+// original Deployment, not the canary Deployment's own name).
+func (s Canary) waitReady(selector string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	checker := statuscheck.NewChecker(s.Client, statuscheck.Options{})
+	w := waiter.New(s.Client, waiter.DefaultBackoff)
+
+	return w.WaitForPods(ctx, s.Namespace, selector, func(obj runtime.Object) (bool, error) {
+		return checker.IsReady(obj)
+	})
+}
+
+func withLabel(labels map[string]string, k, v string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for key, val := range labels {
+		out[key] = val
+	}
+	out[k] = v
+	return out
+}
@@ -0,0 +1,180 @@
+// Package waiter blocks until a predicate is satisfied for watched
+// resources, preferring an informer over fixed-interval polling so it isn't
+// blind between checks and doesn't re-List the API server on every tick.
+package waiter
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Predicate reports whether a single watched object has reached the desired
+// state.
+type Predicate func(obj runtime.Object) (done bool, err error)
+
+// Backoff configures the jittered exponential backoff applied when a
+// Predicate returns a transient error.
+type Backoff struct {
+	Initial time.Duration
+	Factor  float64
+	Max     time.Duration
+}
+
+// DefaultBackoff starts at 100ms, doubles each attempt, and caps at 30s.
+var DefaultBackoff = Backoff{Initial: 100 * time.Millisecond, Factor: 2, Max: 30 * time.Second}
+
+// Waiter blocks until every Pod matching a label selector satisfies a
+// Predicate.
+type Waiter struct {
+	client  kubernetes.Interface
+	backoff Backoff
+}
+
+// New returns a Waiter backed by client. A zero Backoff selects DefaultBackoff.
+func New(client kubernetes.Interface, backoff Backoff) *Waiter {
+	if backoff == (Backoff{}) {
+		backoff = DefaultBackoff
+	}
+	return &Waiter{client: client, backoff: backoff}
+}
+
+// WaitForPods blocks until pred is true for every Pod matching selector in
+// namespace, or ctx is done, whichever comes first. It is driven by a
+// SharedInformerFactory: pred is re-evaluated against the informer's local
+// store on every add/update/delete rather than by re-Listing the cluster.
+func (w *Waiter) WaitForPods(ctx context.Context, namespace, selector string, pred Predicate) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(w.client, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector
+		}),
+	)
+	informer := factory.Core().V1().Pods().Informer()
+
+	result := make(chan error, 1)
+	report := func(err error) {
+		select {
+		case result <- err:
+		default:
+		}
+	}
+
+	evaluate := func() {
+		done, err := retryTransient(ctx, w.backoff, func() (bool, error) {
+			objs := informer.GetStore().List()
+			if len(objs) == 0 {
+				// Nothing matches selector yet (pods not created, or a
+				// selector that matches nothing) - not ready.
+				return false, nil
+			}
+			for _, obj := range objs {
+				done, err := pred(obj.(runtime.Object))
+				if err != nil || !done {
+					return done, err
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			report(err)
+			return
+		}
+		if done {
+			report(nil)
+		}
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { evaluate() },
+		UpdateFunc: func(interface{}, interface{}) { evaluate() },
+		DeleteFunc: func(interface{}) { evaluate() },
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go factory.Start(stop)
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return ctx.Err()
+	}
+	evaluate()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WatchForPod is the fallback for single-shot CLI use, where standing up an
+// informer isn't warranted: it opens one Watch on the named Pod and
+// evaluates pred against each event until satisfied or ctx is done.
+func (w *Waiter) WatchForPod(ctx context.Context, namespace, name string, pred Predicate) error {
+	watcher, err := w.client.CoreV1().Pods(namespace).Watch(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("waiter: watching pod %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("waiter: watch for pod %s closed before becoming ready", name)
+			}
+			if event.Type == watch.Error {
+				continue
+			}
+			done, err := pred(event.Object)
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// retryTransient calls fn, retrying with jittered exponential backoff only
+// while it returns an error, until it returns a nil error (done or not) or
+// ctx is done.
+func retryTransient(ctx context.Context, b Backoff, fn func() (bool, error)) (bool, error) {
+	delay := b.Initial
+	for {
+		done, err := fn()
+		if err == nil {
+			return done, nil
+		}
+
+		select {
+		case <-time.After(jitter(delay)):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+
+		delay = time.Duration(float64(delay) * b.Factor)
+		if delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
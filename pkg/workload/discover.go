@@ -0,0 +1,57 @@
+package workload
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+)
+
+// Discover resolves resource (a Kind or resource name such as "deployment",
+// "deploy", "statefulset", or "daemonset") against the cluster's discovered
+// API groups, then fetches name in namespace as a Workload. Going through a
+// RESTMapper instead of hardcoding e.g. Deployments("default") means this
+// keeps working as new API groups are added to the cluster — all it takes
+// to support a new Kind (such as an argoproj Rollout) is a Workload
+// implementation for it, not a change here.
+func Discover(client kubernetes.Interface, namespace, resource, name string) (Workload, error) {
+	groupResources, err := restmapper.GetAPIGroupResources(client.Discovery())
+	if err != nil {
+		return nil, fmt.Errorf("workload: discovering API groups: %w", err)
+	}
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(groupResources), client.Discovery())
+
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: resource})
+	if err != nil {
+		return nil, fmt.Errorf("workload: resolving resource %q: %w", resource, err)
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return nil, fmt.Errorf("workload: resolving Kind for %s: %w", gvr, err)
+	}
+
+	switch gvk.Kind {
+	case "Deployment":
+		d, err := client.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("workload: get deployment %s: %w", name, err)
+		}
+		return NewDeployment(client, d), nil
+	case "StatefulSet":
+		s, err := client.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("workload: get statefulset %s: %w", name, err)
+		}
+		return NewStatefulSet(client, s), nil
+	case "DaemonSet":
+		ds, err := client.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("workload: get daemonset %s: %w", name, err)
+		}
+		return NewDaemonSet(client, ds), nil
+	default:
+		return nil, fmt.Errorf("workload: no Workload implementation for Kind %s (resolved from %q) — add one alongside Deployment/StatefulSet/DaemonSet to support it, e.g. argoproj Rollouts", gvk.Kind, resource)
+	}
+}
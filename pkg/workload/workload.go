@@ -0,0 +1,274 @@
+// Package workload generalizes rollout/rollback across the Kinds that carry
+// a pod template, so the same logic that used to assume "Deployment" can
+// run against a StatefulSet or DaemonSet too. It replaces the chunk's
+// extensions/v1beta1 Deployment pin, which has been removed since
+// Kubernetes 1.16, with apps/v1.
+package workload
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// revisionAnnotation is set by the Deployment controller on every
+// ReplicaSet it creates, recording which Deployment generation it matches.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// podTemplateHashLabel is stamped onto a ReplicaSet's pod template by the
+// Deployment controller; it must not leak back into the Deployment's own
+// template on rollback.
+const podTemplateHashLabel = "pod-template-hash"
+
+// Workload is a rollout-capable resource: something with a pod template
+// that can be read, updated, and rolled back to a prior revision.
+// Deployment, StatefulSet, and DaemonSet implement it; an argoproj Rollout
+// could too, behind the same interface, without touching callers.
+type Workload interface {
+	GetPodTemplate() corev1.PodTemplateSpec
+	SetPodTemplate(corev1.PodTemplateSpec)
+	ObservedRevision() int64
+	// Save persists a patched pod template, retrying on conflict.
+	Save() error
+	// Rollback reverts the pod template to the given revision.
+	Rollback(toRevision int64) error
+	// Selector is the label selector the workload uses to find its own
+	// pods. Deployment, StatefulSet, and DaemonSet pods aren't required to
+	// carry an "app" label, so callers waiting on readiness must use this
+	// instead of assuming one.
+	Selector() *metav1.LabelSelector
+}
+
+type deploymentWorkload struct {
+	client kubernetes.Interface
+	obj    *appsv1.Deployment
+}
+
+// NewDeployment wraps d so it can be driven through the Workload interface.
+func NewDeployment(client kubernetes.Interface, d *appsv1.Deployment) Workload {
+	return &deploymentWorkload{client: client, obj: d}
+}
+
+func (w *deploymentWorkload) GetPodTemplate() corev1.PodTemplateSpec { return w.obj.Spec.Template }
+
+func (w *deploymentWorkload) SetPodTemplate(t corev1.PodTemplateSpec) { w.obj.Spec.Template = t }
+
+func (w *deploymentWorkload) Selector() *metav1.LabelSelector { return w.obj.Spec.Selector }
+
+func (w *deploymentWorkload) ObservedRevision() int64 {
+	rev, _ := revisionFromAnnotations(w.obj.Annotations)
+	return rev
+}
+
+func (w *deploymentWorkload) Save() error {
+	ns, name := w.obj.Namespace, w.obj.Name
+	template := w.obj.Spec.Template
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := w.client.AppsV1().Deployments(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("workload: get deployment %s: %w", name, err)
+		}
+		current.Spec.Template = template
+		updated, err := w.client.AppsV1().Deployments(ns).Update(current)
+		if err == nil {
+			w.obj = updated
+		}
+		return err
+	})
+}
+
+func (w *deploymentWorkload) Rollback(toRevision int64) error {
+	selector, err := metav1.LabelSelectorAsSelector(w.obj.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("workload: invalid selector on %s: %w", w.obj.Name, err)
+	}
+	rsList, err := w.client.AppsV1().ReplicaSets(w.obj.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("workload: listing ReplicaSets for %s: %w", w.obj.Name, err)
+	}
+
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if !metav1.IsControlledBy(rs, w.obj) {
+			continue
+		}
+		rev, err := revisionFromAnnotations(rs.Annotations)
+		if err != nil || rev != toRevision {
+			continue
+		}
+		template := *rs.Spec.Template.DeepCopy()
+		// pod-template-hash is stamped on by the Deployment controller
+		// itself; kubectl rollout undo strips it before applying so it
+		// doesn't end up managed as part of the Deployment's own template.
+		delete(template.Labels, podTemplateHashLabel)
+		w.SetPodTemplate(template)
+		return w.Save()
+	}
+	return fmt.Errorf("workload: no ReplicaSet found for revision %d of %s", toRevision, w.obj.Name)
+}
+
+type statefulSetWorkload struct {
+	client kubernetes.Interface
+	obj    *appsv1.StatefulSet
+}
+
+// NewStatefulSet wraps s so it can be driven through the Workload interface.
+func NewStatefulSet(client kubernetes.Interface, s *appsv1.StatefulSet) Workload {
+	return &statefulSetWorkload{client: client, obj: s}
+}
+
+func (w *statefulSetWorkload) GetPodTemplate() corev1.PodTemplateSpec { return w.obj.Spec.Template }
+
+func (w *statefulSetWorkload) SetPodTemplate(t corev1.PodTemplateSpec) { w.obj.Spec.Template = t }
+
+func (w *statefulSetWorkload) Selector() *metav1.LabelSelector { return w.obj.Spec.Selector }
+
+func (w *statefulSetWorkload) ObservedRevision() int64 {
+	rev, err := controllerRevisionNumberFor(w.client, w.obj.Namespace, w.obj.Spec.Selector, w.obj.Status.UpdateRevision)
+	if err != nil {
+		return 0
+	}
+	return rev
+}
+
+func (w *statefulSetWorkload) Save() error {
+	ns, name := w.obj.Namespace, w.obj.Name
+	template := w.obj.Spec.Template
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := w.client.AppsV1().StatefulSets(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("workload: get statefulset %s: %w", name, err)
+		}
+		current.Spec.Template = template
+		updated, err := w.client.AppsV1().StatefulSets(ns).Update(current)
+		if err == nil {
+			w.obj = updated
+		}
+		return err
+	})
+}
+
+func (w *statefulSetWorkload) Rollback(toRevision int64) error {
+	return applyControllerRevision(w.client, w.obj.Namespace, w.obj.Name, w.obj, w.obj.Spec.Selector, toRevision,
+		func(patch []byte) error {
+			_, err := w.client.AppsV1().StatefulSets(w.obj.Namespace).Patch(w.obj.Name, types.StrategicMergePatchType, patch)
+			return err
+		})
+}
+
+type daemonSetWorkload struct {
+	client kubernetes.Interface
+	obj    *appsv1.DaemonSet
+}
+
+// NewDaemonSet wraps d so it can be driven through the Workload interface.
+func NewDaemonSet(client kubernetes.Interface, d *appsv1.DaemonSet) Workload {
+	return &daemonSetWorkload{client: client, obj: d}
+}
+
+func (w *daemonSetWorkload) GetPodTemplate() corev1.PodTemplateSpec { return w.obj.Spec.Template }
+
+func (w *daemonSetWorkload) SetPodTemplate(t corev1.PodTemplateSpec) { w.obj.Spec.Template = t }
+
+func (w *daemonSetWorkload) Selector() *metav1.LabelSelector { return w.obj.Spec.Selector }
+
+func (w *daemonSetWorkload) ObservedRevision() int64 {
+	return int64(w.obj.Status.ObservedGeneration)
+}
+
+func (w *daemonSetWorkload) Save() error {
+	ns, name := w.obj.Namespace, w.obj.Name
+	template := w.obj.Spec.Template
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := w.client.AppsV1().DaemonSets(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("workload: get daemonset %s: %w", name, err)
+		}
+		current.Spec.Template = template
+		updated, err := w.client.AppsV1().DaemonSets(ns).Update(current)
+		if err == nil {
+			w.obj = updated
+		}
+		return err
+	})
+}
+
+func (w *daemonSetWorkload) Rollback(toRevision int64) error {
+	return applyControllerRevision(w.client, w.obj.Namespace, w.obj.Name, w.obj, w.obj.Spec.Selector, toRevision,
+		func(patch []byte) error {
+			_, err := w.client.AppsV1().DaemonSets(w.obj.Namespace).Patch(w.obj.Name, types.StrategicMergePatchType, patch)
+			return err
+		})
+}
+
+// applyControllerRevision finds the ControllerRevision owned by owner that
+// matches toRevision and hands its stored strategic-merge patch to apply.
+// StatefulSet and DaemonSet record history as ControllerRevisions rather
+// than ReplicaSets, so rollback means replaying that patch rather than
+// reconstructing a pod template by hand.
+func applyControllerRevision(client kubernetes.Interface, namespace, name string, owner metav1.Object, selector *metav1.LabelSelector, toRevision int64, apply func(patch []byte) error) error {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return fmt.Errorf("workload: invalid selector on %s: %w", name, err)
+	}
+
+	revisions, err := client.AppsV1().ControllerRevisions(namespace).List(metav1.ListOptions{
+		LabelSelector: sel.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("workload: listing ControllerRevisions for %s: %w", name, err)
+	}
+
+	for i := range revisions.Items {
+		rev := &revisions.Items[i]
+		if !metav1.IsControlledBy(rev, owner) || rev.Revision != toRevision {
+			continue
+		}
+		return apply(rev.Data.Raw)
+	}
+	return fmt.Errorf("workload: no ControllerRevision found for revision %d of %s", toRevision, name)
+}
+
+func revisionFromAnnotations(annotations map[string]string) (int64, error) {
+	v, ok := annotations[revisionAnnotation]
+	if !ok {
+		return 0, fmt.Errorf("workload: missing %s annotation", revisionAnnotation)
+	}
+	var rev int64
+	if _, err := fmt.Sscanf(v, "%d", &rev); err != nil {
+		return 0, fmt.Errorf("workload: invalid %s annotation %q: %w", revisionAnnotation, v, err)
+	}
+	return rev, nil
+}
+
+// controllerRevisionNumberFor resolves a StatefulSet's UpdateRevision/
+// CurrentRevision name (e.g. "redis-6d7d8d9f7") to its numeric .Revision by
+// looking up the matching ControllerRevision, since the name itself doesn't
+// encode the number.
+func controllerRevisionNumberFor(client kubernetes.Interface, namespace string, selector *metav1.LabelSelector, revisionName string) (int64, error) {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return 0, fmt.Errorf("workload: invalid selector: %w", err)
+	}
+
+	revisions, err := client.AppsV1().ControllerRevisions(namespace).List(metav1.ListOptions{
+		LabelSelector: sel.String(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("workload: listing ControllerRevisions: %w", err)
+	}
+
+	for i := range revisions.Items {
+		if revisions.Items[i].Name == revisionName {
+			return revisions.Items[i].Revision, nil
+		}
+	}
+	return 0, fmt.Errorf("workload: no ControllerRevision named %s", revisionName)
+}